@@ -0,0 +1,137 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+// Package helper contains helper functions for working with the vSphere
+// provider API types.
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+
+	apisvsphere "github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// FindRegion takes a region name and a CloudProfileConfig and returns the
+// region matching the given name. If no such region is found, nil is
+// returned.
+func FindRegion(regionName string, cloudProfileConfig *apisvsphere.CloudProfileConfig) *apisvsphere.Region {
+	if cloudProfileConfig == nil {
+		return nil
+	}
+	for _, region := range cloudProfileConfig.Regions {
+		if region.Name == regionName {
+			return &region
+		}
+	}
+	return nil
+}
+
+// FindVCenter returns the VCenter with the given name from the region. If
+// name is empty and the region declares no VCenters (legacy single-vCenter
+// configuration), a VCenter synthesized from the region's deprecated
+// VsphereHost/VsphereInsecureSSL fields is returned under the name
+// apisvsphere.DefaultVCenterName. If name is empty and the region declares
+// exactly one VCenter, that VCenter is returned.
+func FindVCenter(region *apisvsphere.Region, name string) (*apisvsphere.VCenter, error) {
+	if len(region.VCenters) == 0 {
+		if name != "" && name != apisvsphere.DefaultVCenterName {
+			return nil, fmt.Errorf("vCenter %q not found in region %q", name, region.Name)
+		}
+		return &apisvsphere.VCenter{
+			Name:        apisvsphere.DefaultVCenterName,
+			Host:        region.VsphereHost,
+			InsecureSSL: region.VsphereInsecureSSL,
+		}, nil
+	}
+
+	if name == "" {
+		if len(region.VCenters) == 1 {
+			return &region.VCenters[0], nil
+		}
+		return nil, fmt.Errorf("region %q has multiple vCenters, zone must specify one", region.Name)
+	}
+
+	for i := range region.VCenters {
+		if region.VCenters[i].Name == name {
+			return &region.VCenters[i], nil
+		}
+	}
+	return nil, fmt.Errorf("vCenter %q not found in region %q", name, region.Name)
+}
+
+// FindMachineImageVersion returns the MachineImageVersion matching the
+// given image name and version from the CloudProfileConfig.
+func FindMachineImageVersion(cloudProfileConfig *apisvsphere.CloudProfileConfig, name, version string) (*apisvsphere.MachineImageVersion, error) {
+	for _, image := range cloudProfileConfig.MachineImages {
+		if image.Name != name {
+			continue
+		}
+		for i := range image.Versions {
+			if image.Versions[i].Version == version {
+				return &image.Versions[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("machine image %q version %q not found in cloud profile", name, version)
+}
+
+// DecodeCloudProfileConfig decodes the given raw extension into a
+// CloudProfileConfig object.
+func DecodeCloudProfileConfig(rawExtension *runtime.RawExtension) (*apisvsphere.CloudProfileConfig, error) {
+	if rawExtension == nil || len(rawExtension.Raw) == 0 {
+		return nil, fmt.Errorf("cloud profile config is empty")
+	}
+
+	cloudProfileConfig := &apisvsphere.CloudProfileConfig{}
+	if err := json.Unmarshal(rawExtension.Raw, cloudProfileConfig); err != nil {
+		return nil, fmt.Errorf("could not decode cloud profile config: %v", err)
+	}
+
+	return cloudProfileConfig, nil
+}
+
+// DecodeWorkerConfig decodes the given raw extension into a WorkerConfig
+// object. A nil raw extension decodes to an empty, non-nil WorkerConfig.
+func DecodeWorkerConfig(rawExtension *runtime.RawExtension) (*apisvsphere.WorkerConfig, error) {
+	workerConfig := &apisvsphere.WorkerConfig{}
+	if rawExtension == nil || len(rawExtension.Raw) == 0 {
+		return workerConfig, nil
+	}
+
+	if err := json.Unmarshal(rawExtension.Raw, workerConfig); err != nil {
+		return nil, fmt.Errorf("could not decode worker pool provider config: %v", err)
+	}
+
+	return workerConfig, nil
+}
+
+// GetInfrastructureStatus decodes the given raw extension into an
+// InfrastructureStatus object.
+func GetInfrastructureStatus(name string, rawExtension *runtime.RawExtension) (*apisvsphere.InfrastructureStatus, error) {
+	if rawExtension == nil {
+		return nil, fmt.Errorf("infrastructure provider status of worker %q is empty", name)
+	}
+
+	infrastructureStatus := &apisvsphere.InfrastructureStatus{}
+	if err := json.Unmarshal(rawExtension.Raw, infrastructureStatus); err != nil {
+		return nil, fmt.Errorf("could not decode infrastructure status of worker %q: %v", name, err)
+	}
+
+	return infrastructureStatus, nil
+}
@@ -0,0 +1,325 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+// Package vsphere contains the provider specific API types for the vSphere
+// Gardener extension (CloudProfileConfig, InfrastructureConfig/Status, and
+// WorkerConfig/Status).
+package vsphere
+
+// DefaultVCenterName is the synthesized name of a region's sole vCenter
+// when the region still uses the deprecated single-vCenter fields.
+const DefaultVCenterName = "default"
+
+// CloudProfileConfig contains provider-specific configuration that is embedded
+// into the Gardener CloudProfile resource.
+type CloudProfileConfig struct {
+	// Regions are the vSphere regions supported by this cloud profile.
+	Regions []Region `json:"regions"`
+	// MachineImages is the list of machine images that are understood by the
+	// controller.
+	MachineImages []MachineImages `json:"machineImages"`
+}
+
+// Region contains the connection details for the vCenter(s) serving a
+// Gardener region.
+type Region struct {
+	// Name is the name of the region as referenced in the Shoot.
+	Name string `json:"name"`
+	// VsphereHost is the hostname or IP address of the vCenter server.
+	//
+	// Deprecated: use VCenters instead. This field is only honored when
+	// VCenters is empty, and is then treated as a single VCenter entry
+	// named "default".
+	VsphereHost string `json:"vsphereHost,omitempty"`
+	// VsphereInsecureSSL disables certificate verification against the
+	// vCenter server if set to true.
+	//
+	// Deprecated: use VCenters instead.
+	VsphereInsecureSSL bool `json:"vsphereInsecureSSL,omitempty"`
+	// VCenters is the list of vCenters backing this region. A region with
+	// more than one entry spans multiple failure domains, and zones select
+	// their vCenter via ZoneConfig.VCenter.
+	VCenters []VCenter `json:"vCenters,omitempty"`
+}
+
+// VCenter identifies a single vCenter server backing a region by name, host
+// and TLS setting. It carries no credentials, datacenter or topology tags:
+// credentials are looked up by Name in the worker's secret, and placement
+// comes from the ZoneConfig that references this vCenter.
+type VCenter struct {
+	// Name identifies this vCenter within the region. It is referenced by
+	// ZoneConfig.VCenter and by the named credential block in the worker's
+	// secret.
+	Name string `json:"name"`
+	// Host is the hostname or IP address of the vCenter server.
+	Host string `json:"host"`
+	// InsecureSSL disables certificate verification against this vCenter
+	// if set to true.
+	InsecureSSL bool `json:"insecureSSL,omitempty"`
+}
+
+// MachineImages is a mapping from a machine image name to a list of
+// available versions for that image.
+type MachineImages struct {
+	// Name is the name of the machine image.
+	Name string `json:"name"`
+	// Versions contains the list of versions available for this image.
+	Versions []MachineImageVersion `json:"versions"`
+}
+
+// MachineImageVersion contains the version-specific configuration for a
+// machine image, i.e. where the backing template can be found.
+type MachineImageVersion struct {
+	// Version is the version of the image.
+	Version string `json:"version"`
+	// Path is the inventory path of an already-uploaded template VM that
+	// this image resolves to. If set, it is preferred over ContentLibrary
+	// resolution.
+	Path string `json:"path,omitempty"`
+	// GuestID is the guest OS identifier to set on VMs cloned from this
+	// image.
+	GuestID string `json:"guestId,omitempty"`
+	// ContentLibrary is the name of the vSphere Content Library that this
+	// image's item is looked up in, keyed by name and Version, when Path
+	// is not set.
+	ContentLibrary string `json:"contentLibrary,omitempty"`
+	// OVAURL is the URL that the image is imported from into
+	// ContentLibrary on a cache-miss (the named item does not yet exist in
+	// the library).
+	OVAURL string `json:"ovaUrl,omitempty"`
+	// SHA256 is the expected checksum of the OVA/OVF at OVAURL. It is only
+	// checked when the image is imported into ContentLibrary on a
+	// cache-miss; an item that already exists in the library is trusted
+	// as-is. Import fails if the downloaded OVA's checksum does not match.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// MachineImage is the resolved machine image for a worker pool, as computed
+// by the worker delegate for a single pool/zone combination.
+type MachineImage struct {
+	// Name is the name of the machine image.
+	Name string `json:"name"`
+	// Version is the version of the machine image.
+	Version string `json:"version"`
+	// Path is the inventory path of the resolved template VM.
+	Path string `json:"path"`
+	// GuestID is the guest OS identifier of the resolved template VM.
+	GuestID string `json:"guestId,omitempty"`
+	// ContentLibrary is the name of the Content Library the image's item
+	// was resolved from, if it was not already an uploaded template VM.
+	ContentLibrary string `json:"contentLibrary,omitempty"`
+}
+
+// InfrastructureStatus contains information about the created vSphere
+// infrastructure resources.
+type InfrastructureStatus struct {
+	// VsphereConfig contains the zonal placement configuration that was
+	// derived during infrastructure reconciliation.
+	VsphereConfig VsphereConfig `json:"vsphereConfig"`
+	// NSXTInfraState contains the NSX-T resources created for this shoot.
+	NSXTInfraState *NSXTInfraState `json:"nsxtInfraState,omitempty"`
+}
+
+// VsphereConfig contains the zonal placement configuration for a shoot.
+type VsphereConfig struct {
+	// Region is the vSphere region this shoot is placed in.
+	Region string `json:"region"`
+	// Folder is the VM folder in which machines are placed.
+	Folder string `json:"folder,omitempty"`
+	// ZoneConfigs maps a Gardener zone name to its vSphere placement
+	// configuration.
+	ZoneConfigs map[string]ZoneConfig `json:"zoneConfigs"`
+}
+
+// ZoneConfig contains the vSphere placement configuration for a single
+// availability zone.
+type ZoneConfig struct {
+	// VCenter is the name of the VCenter entry (see Region.VCenters) that
+	// this zone is placed in. If empty, the region's sole/default vCenter
+	// is used.
+	VCenter string `json:"vCenter,omitempty"`
+	// Datacenter is the inventory path of the datacenter that this zone
+	// maps to.
+	Datacenter string `json:"datacenter"`
+	// ComputeCluster is the inventory path of the compute cluster that
+	// machines in this zone are placed in.
+	ComputeCluster string `json:"computeCluster,omitempty"`
+	// HostSystem is the inventory path of a single ESXi host that machines
+	// in this zone are pinned to.
+	HostSystem string `json:"hostSystem,omitempty"`
+	// ResourcePool is the inventory path of the resource pool that
+	// machines in this zone are placed in.
+	ResourcePool string `json:"resourcePool,omitempty"`
+	// Datastore is the name of the datastore that machines in this zone
+	// are placed on.
+	Datastore string `json:"datastore,omitempty"`
+	// DatastoreCluster is the name of the datastore cluster that machines
+	// in this zone are placed on.
+	DatastoreCluster string `json:"datastoreCluster,omitempty"`
+	// SwitchUUID is the UUID of the distributed virtual switch backing the
+	// network segment for this zone.
+	SwitchUUID string `json:"switchUuid,omitempty"`
+	// HostGroup is the name of a pre-existing DRS host group that VMs
+	// placed in this zone must run on. When set, a VM group and a
+	// "must run on" VM-Host affinity rule linking the two are reconciled
+	// by pkg/vsphere/hostgroup for every MachineDeployment in this zone.
+	HostGroup string `json:"hostGroup,omitempty"`
+	// MTU is the MTU configured on the NSX-T segment backing this zone. It
+	// is used to render the NoCloud network-config for machines that
+	// request static IP addressing, and defaults to 1500 if unset.
+	MTU int32 `json:"mtu,omitempty"`
+}
+
+// NSXTInfraState contains the NSX-T resources created for a shoot's
+// infrastructure.
+type NSXTInfraState struct {
+	// SegmentName is the name of the NSX-T segment that machines are
+	// attached to.
+	SegmentName *string `json:"segmentName,omitempty"`
+}
+
+// WorkerStatus contains information about created worker resources.
+type WorkerStatus struct {
+	// MachineImages is the list of machine images that were used to
+	// create the worker pools.
+	MachineImages []MachineImage `json:"machineImages,omitempty"`
+}
+
+// WorkerConfig contains provider-specific configuration for a single worker
+// pool, as passed via `Worker.Spec.Pools[].ProviderConfig`.
+type WorkerConfig struct {
+	// MachinePool, if set, opts this worker pool into vSphere-native
+	// placement: each (pool, zone) MachineDeployment places its machines in
+	// a dedicated VM folder, and a DRS anti-affinity rule over the VMs in
+	// that folder is reconciled via govmomi. The pool still gets one
+	// MachineClass and MachineDeployment per (pool, zone), and machines are
+	// still created and scaled by MCM.
+	MachinePool *MachinePoolConfig `json:"machinePool,omitempty"`
+	// StaticIPAM, if set, opts this worker pool out of DHCP-based
+	// bootstrap: the pool's machine class carries this configuration under
+	// "ipamPool" so that the MCM vSphere driver allocates a static address
+	// from the configured IPAM source, per VM it creates, instead of
+	// relying on the NSX-T segment's DHCP scope.
+	StaticIPAM *StaticIPAMConfig `json:"staticIPAM,omitempty"`
+}
+
+// StaticIPAMConfig selects and configures the IPAM source that static
+// addresses for a worker pool's machines are drawn from.
+type StaticIPAMConfig struct {
+	// Source selects the backing IPAM system.
+	Source IPAMSource `json:"source"`
+	// InCluster configures the in-cluster IPAM source. Required when
+	// Source is IPAMSourceInCluster.
+	InCluster *InClusterIPAMSource `json:"inCluster,omitempty"`
+	// Infoblox configures the Infoblox IPAM source. Required when Source
+	// is IPAMSourceInfoblox.
+	Infoblox *InfobloxIPAMSource `json:"infoblox,omitempty"`
+	// PhpIPAM configures the phpIPAM source. Required when Source is
+	// IPAMSourcePhpIPAM.
+	PhpIPAM *PhpIPAMSource `json:"phpIPAM,omitempty"`
+}
+
+// IPAMSource is the backing system that static IP addresses are allocated
+// from.
+type IPAMSource string
+
+const (
+	// IPAMSourceInCluster allocates addresses from an in-cluster IP pool
+	// ConfigMap (a plain ConfigMap, not a custom resource).
+	IPAMSourceInCluster IPAMSource = "InCluster"
+	// IPAMSourceInfoblox allocates addresses from an external Infoblox
+	// appliance.
+	IPAMSourceInfoblox IPAMSource = "Infoblox"
+	// IPAMSourcePhpIPAM allocates addresses from an external phpIPAM
+	// instance.
+	IPAMSourcePhpIPAM IPAMSource = "PhpIPAM"
+)
+
+// InClusterIPAMSource references the in-cluster IP pool that addresses are
+// allocated from.
+type InClusterIPAMSource struct {
+	// PoolName is the name of the IP pool ConfigMap, in the shoot
+	// namespace, to allocate from (see ipam.NewInClusterAllocator for the
+	// keys it must carry).
+	PoolName string `json:"poolName"`
+}
+
+// InfobloxIPAMSource configures access to an Infoblox WAPI endpoint.
+type InfobloxIPAMSource struct {
+	// Host is the Infoblox grid master hostname.
+	Host string `json:"host"`
+	// NetworkView is the Infoblox network view to allocate from.
+	NetworkView string `json:"networkView"`
+	// CredentialsSecretName is the name of a secret, in the shoot
+	// namespace, holding the "username" and "password" used to
+	// authenticate against the WAPI endpoint.
+	CredentialsSecretName string `json:"credentialsSecretName"`
+}
+
+// PhpIPAMSource configures access to a phpIPAM REST API endpoint.
+type PhpIPAMSource struct {
+	// Endpoint is the base URL of the phpIPAM REST API.
+	Endpoint string `json:"endpoint"`
+	// SectionID is the phpIPAM section to allocate from.
+	SectionID string `json:"sectionId"`
+	// AppID is the phpIPAM application ID used to authenticate.
+	AppID string `json:"appId"`
+	// CredentialsSecretName is the name of a secret, in the shoot
+	// namespace, holding the "username" and "password" used to
+	// authenticate against the REST API.
+	CredentialsSecretName string `json:"credentialsSecretName"`
+}
+
+// MachinePoolConfig configures vSphere-native placement for a worker pool
+// that opts out of plain per-VM MCM scaling.
+type MachinePoolConfig struct {
+	// MinReadySeconds, if greater than zero, is the interval in seconds
+	// after which the pool's folder and anti-affinity rule are reconciled
+	// again, so that VMs which joined or left the pool's folder since the
+	// previous pass are picked up. If zero, they are only reconciled again
+	// when the MachineDeployment changes.
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+	// AntiAffinityPolicy controls how replicas of this pool are spread
+	// across ESXi hosts in the compute cluster.
+	AntiAffinityPolicy AntiAffinityPolicy `json:"antiAffinityPolicy,omitempty"`
+	// FolderTemplate configures the VM folder that is created to hold the
+	// pool's machines.
+	FolderTemplate *FolderTemplate `json:"folderTemplate,omitempty"`
+}
+
+// AntiAffinityPolicy is the DRS anti-affinity strategy applied to a
+// MachinePool's VM group.
+type AntiAffinityPolicy string
+
+const (
+	// AntiAffinityPolicyNone applies no DRS anti-affinity rule.
+	AntiAffinityPolicyNone AntiAffinityPolicy = "None"
+	// AntiAffinityPolicySpreadHosts creates a "separate virtual machines"
+	// DRS rule so that no two replicas of the pool run on the same ESXi
+	// host.
+	AntiAffinityPolicySpreadHosts AntiAffinityPolicy = "SpreadHosts"
+)
+
+// FolderTemplate configures the VM folder that backs a MachinePool.
+type FolderTemplate struct {
+	// NameTemplate is a Go template string evaluated with the pool name
+	// and namespace to derive the folder name. Defaults to
+	// "{{.Namespace}}-{{.Pool}}" if empty.
+	NameTemplate string `json:"nameTemplate,omitempty"`
+	// Parent is the inventory path of the parent folder under which the
+	// pool folder is created. Defaults to the shoot's VM folder.
+	Parent string `json:"parent,omitempty"`
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+// Package machinepool contains a controller that reconciles the vSphere-native
+// placement resources (VM folder, DRS anti-affinity rule) for worker pools
+// that opt into MachinePool management. It does not write replica counts or
+// pool membership back to the MachineDeployment status.
+package machinepool
+
+import (
+	"context"
+
+	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
+
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// ControllerName is the name of this controller.
+const ControllerName = "machinepool"
+
+// AddOptions are options to apply when adding the machinepool controller to
+// the manager.
+type AddOptions struct {
+	// ConcurrentSyncs is the number of concurrent worker routines for this
+	// controller.
+	ConcurrentSyncs int
+}
+
+// AddToManager adds the machinepool controller to the given manager.
+func AddToManager(ctx context.Context, mgr manager.Manager, opts AddOptions) error {
+	ctrl, err := controller.New(ControllerName, mgr, controller.Options{
+		Reconciler:              NewReconciler(mgr),
+		MaxConcurrentReconciles: opts.ConcurrentSyncs,
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctrl.Watch(&source.Kind{Type: &machinev1alpha1.MachineDeployment{}}, &handler.EnqueueRequestForObject{})
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package machinepool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	apisvsphere "github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere"
+	"github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere/helper"
+	"github.com/gardener/gardener-extension-provider-vsphere/pkg/vsphere"
+	vspheremachinepool "github.com/gardener/gardener-extension-provider-vsphere/pkg/vsphere/machinepool"
+
+	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// State is the JSON payload stored under machinepool.Annotation on a
+// MachineDeployment that opts into MachinePool reconciliation. It is
+// populated by the worker controller (see pkg/controller/worker) from the
+// pool's MachinePoolConfig and the zone it was generated for.
+type State struct {
+	Pool               string                         `json:"pool"`
+	VCenter            string                         `json:"vCenter"`
+	ComputeCluster     string                         `json:"computeCluster"`
+	FolderParent       string                         `json:"folderParent"`
+	FolderName         string                         `json:"folderName"`
+	MinReadySeconds    int32                          `json:"minReadySeconds,omitempty"`
+	AntiAffinityPolicy apisvsphere.AntiAffinityPolicy `json:"antiAffinityPolicy,omitempty"`
+}
+
+type reconciler struct {
+	client client.Client
+	logger logr.Logger
+}
+
+// NewReconciler creates a new reconciler that reconciles the vSphere-native
+// placement resources for MachineDeployments annotated as a MachinePool.
+func NewReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &reconciler{
+		client: mgr.GetClient(),
+		logger: mgr.GetLogger().WithName(ControllerName),
+	}
+}
+
+// Reconcile ensures that the VM folder and DRS anti-affinity rule for a
+// MachinePool-annotated MachineDeployment are up to date.
+func (r *reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	deployment := &machinev1alpha1.MachineDeployment{}
+	if err := r.client.Get(ctx, req.NamespacedName, deployment); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	rawState, ok := deployment.Annotations[vspheremachinepool.Annotation]
+	if !ok {
+		return reconcile.Result{}, nil
+	}
+
+	var state State
+	if err := json.Unmarshal([]byte(rawState), &state); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "could not decode machine pool state for %q", req.NamespacedName)
+	}
+
+	finder, clusterComputeResource, cleanup, err := r.connect(ctx, deployment.Namespace, state)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	defer cleanup()
+
+	folderReconciler := vspheremachinepool.NewFolderReconciler(finder)
+	folder, err := folderReconciler.EnsureFolder(ctx, state.FolderParent, state.FolderName)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "could not ensure folder for machine pool %q", state.Pool)
+	}
+
+	vms, err := finder.VirtualMachineList(ctx, folder.InventoryPath+"/*")
+	if err != nil {
+		if _, ok := err.(*find.NotFoundError); !ok {
+			return reconcile.Result{}, errors.Wrapf(err, "could not list VMs in folder %q", folder.InventoryPath)
+		}
+	}
+
+	affinityReconciler := vspheremachinepool.NewAntiAffinityReconciler(clusterComputeResource)
+	if err := affinityReconciler.EnsureRule(ctx, poolRuleName(deployment.Name), state.AntiAffinityPolicy, vmRefs(vms)); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "could not ensure anti-affinity rule for machine pool %q", state.Pool)
+	}
+
+	r.logger.Info("reconciled machine pool placement", "machineDeployment", req.NamespacedName, "folder", folder.InventoryPath, "members", len(vms))
+
+	if state.MinReadySeconds > 0 {
+		return reconcile.Result{RequeueAfter: time.Duration(state.MinReadySeconds) * time.Second}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// poolRuleName derives the DRS anti-affinity rule name for a
+// MachinePool-managed deployment, distinct from the host-group rule naming
+// in pkg/vsphere/hostgroup so both kinds of rule are easy to tell apart in
+// the vCenter UI.
+func poolRuleName(machineDeploymentName string) string {
+	return "mcm-pool-" + machineDeploymentName
+}
+
+// vmRefs returns the managed object references of vms.
+func vmRefs(vms []*object.VirtualMachine) []types.ManagedObjectReference {
+	refs := make([]types.ManagedObjectReference, 0, len(vms))
+	for _, vm := range vms {
+		refs = append(refs, vm.Reference())
+	}
+	return refs
+}
+
+func (r *reconciler) connect(ctx context.Context, namespace string, state State) (*find.Finder, *object.ClusterComputeResource, func(), error) {
+	workers := &extensionsv1alpha1.WorkerList{}
+	if err := r.client.List(ctx, workers, client.InNamespace(namespace)); err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "could not list workers in namespace %q", namespace)
+	}
+	if len(workers.Items) == 0 {
+		return nil, nil, nil, fmt.Errorf("no worker resource found in namespace %q", namespace)
+	}
+	w := workers.Items[0]
+
+	cluster, err := extensionscontroller.GetCluster(ctx, r.client, namespace)
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "could not read cluster for namespace %q", namespace)
+	}
+	cloudProfileConfig, err := helper.DecodeCloudProfileConfig(cluster.CloudProfile.Spec.ProviderConfig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	region := helper.FindRegion(cluster.Shoot.Spec.Region, cloudProfileConfig)
+	if region == nil {
+		return nil, nil, nil, fmt.Errorf("region %q not found", cluster.Shoot.Spec.Region)
+	}
+	vcenter, err := helper.FindVCenter(region, state.VCenter)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	secret, err := extensionscontroller.GetSecretByReference(ctx, r.client, &w.Spec.SecretRef)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	credentials, err := vsphere.ExtractCredentials(secret)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	binding, err := credentials.ForVCenter(vcenter.Name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	govClient, err := vsphere.NewClient(ctx, vcenter.Host, binding.Username, binding.Password, vcenter.InsecureSSL)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	finder := find.NewFinder(govClient.Client, true)
+	clusterComputeResource, err := finder.ClusterComputeResource(ctx, state.ComputeCluster)
+	if err != nil {
+		govClient.Logout(ctx)
+		return nil, nil, nil, errors.Wrapf(err, "could not resolve compute cluster %q", state.ComputeCluster)
+	}
+
+	return finder, clusterComputeResource, func() { govClient.Logout(ctx) }, nil
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	apisvsphere "github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere"
+	"github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere/helper"
+	"github.com/gardener/gardener-extension-provider-vsphere/pkg/vsphere"
+	"github.com/gardener/gardener-extension-provider-vsphere/pkg/vsphere/hostgroup"
+
+	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
+	"github.com/vmware/govmomi/find"
+)
+
+// zoneHostGroup pairs a MachineDeployment with the zone it was generated
+// for, so host-group rules can be reconciled once the deployment names are
+// known.
+type zoneHostGroup struct {
+	machineDeploymentName string
+	zoneConfig            apisvsphere.ZoneConfig
+}
+
+// reconcileHostGroups ensures that the DRS VM group and "must run on"
+// VM-Host affinity rule exist for every MachineDeployment whose zone
+// declares a HostGroup. It is invoked after machine classes and deployments
+// have been computed, as part of DeployMachineClasses.
+func (w *workerDelegate) reconcileHostGroups(ctx context.Context, region *apisvsphere.Region, groups []zoneHostGroup) error {
+	byVCenter := map[string][]zoneHostGroup{}
+	for _, g := range groups {
+		if g.zoneConfig.HostGroup == "" {
+			continue
+		}
+		byVCenter[g.zoneConfig.VCenter] = append(byVCenter[g.zoneConfig.VCenter], g)
+	}
+	if len(byVCenter) == 0 {
+		return nil
+	}
+
+	credentials, err := w.extractCredentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	for vcenterName, groupsForVCenter := range byVCenter {
+		if err := w.reconcileHostGroupsForVCenter(ctx, credentials, region, vcenterName, groupsForVCenter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *workerDelegate) reconcileHostGroupsForVCenter(ctx context.Context, credentials *vsphere.Credentials, region *apisvsphere.Region, vcenterName string, groups []zoneHostGroup) error {
+	vcenter, err := helper.FindVCenter(region, vcenterName)
+	if err != nil {
+		return err
+	}
+
+	secretData, err := generateMachineClassSecretData(credentials, region, vcenterName)
+	if err != nil {
+		return err
+	}
+
+	client, err := vsphere.NewClient(ctx, vcenter.Host, string(secretData[vsphere.Username]), string(secretData[vsphere.Password]), vcenter.InsecureSSL)
+	if err != nil {
+		return err
+	}
+	defer client.Logout(ctx)
+
+	for _, g := range groups {
+		finder := find.NewFinder(client.Client, true)
+		cluster, err := finder.ClusterComputeResource(ctx, g.zoneConfig.ComputeCluster)
+		if err != nil {
+			return fmt.Errorf("could not resolve compute cluster %q: %v", g.zoneConfig.ComputeCluster, err)
+		}
+
+		reconciler := hostgroup.NewReconciler(cluster)
+		vmGroupName := hostgroup.VMGroupName(g.machineDeploymentName)
+		ruleName := hostgroup.RuleName(g.machineDeploymentName)
+
+		if err := reconciler.EnsureVMGroup(ctx, vmGroupName); err != nil {
+			return err
+		}
+		if err := reconciler.EnsureHostRule(ctx, ruleName, vmGroupName, g.zoneConfig.HostGroup); err != nil {
+			return err
+		}
+		if err := w.addMachineDeploymentVMs(ctx, finder, reconciler, vmGroupName, g.machineDeploymentName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addMachineDeploymentVMs adds every VM named after machineDeploymentName
+// (the naming convention the MCM vSphere driver clones machines under) to
+// the given VM group, so replicas actually pick up the zone's "must run
+// on" affinity rule instead of sitting in a permanently empty group. This
+// is a reconcile-loop hook rather than an event-driven one: VMs created
+// since the previous call to DeployMachineClasses are picked up here.
+func (w *workerDelegate) addMachineDeploymentVMs(ctx context.Context, finder *find.Finder, reconciler *hostgroup.Reconciler, vmGroupName, machineDeploymentName string) error {
+	vms, err := finder.VirtualMachineList(ctx, machineDeploymentName+"*")
+	if err != nil {
+		if _, ok := err.(*find.NotFoundError); ok {
+			return nil
+		}
+		return fmt.Errorf("could not list VMs for machine deployment %q: %v", machineDeploymentName, err)
+	}
+
+	for _, vm := range vms {
+		if err := reconciler.AddVM(ctx, vmGroupName, vm.Reference()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanupHostGroups removes the DRS VM group and VM-Host affinity rule for
+// every MachineDeployment whose zone declares a HostGroup. Its only caller
+// is CleanupHostGroupResources, which nothing in this extension calls.
+func (w *workerDelegate) cleanupHostGroups(ctx context.Context, region *apisvsphere.Region, groups []zoneHostGroup) error {
+	byVCenter := map[string][]zoneHostGroup{}
+	for _, g := range groups {
+		if g.zoneConfig.HostGroup == "" {
+			continue
+		}
+		byVCenter[g.zoneConfig.VCenter] = append(byVCenter[g.zoneConfig.VCenter], g)
+	}
+	if len(byVCenter) == 0 {
+		return nil
+	}
+
+	credentials, err := w.extractCredentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	for vcenterName, groupsForVCenter := range byVCenter {
+		vcenter, err := helper.FindVCenter(region, vcenterName)
+		if err != nil {
+			return err
+		}
+
+		secretData, err := generateMachineClassSecretData(credentials, region, vcenterName)
+		if err != nil {
+			return err
+		}
+
+		client, err := vsphere.NewClient(ctx, vcenter.Host, string(secretData[vsphere.Username]), string(secretData[vsphere.Password]), vcenter.InsecureSSL)
+		if err != nil {
+			return err
+		}
+
+		for _, g := range groupsForVCenter {
+			finder := find.NewFinder(client.Client, true)
+			cluster, err := finder.ClusterComputeResource(ctx, g.zoneConfig.ComputeCluster)
+			if err != nil {
+				client.Logout(ctx)
+				return fmt.Errorf("could not resolve compute cluster %q: %v", g.zoneConfig.ComputeCluster, err)
+			}
+
+			reconciler := hostgroup.NewReconciler(cluster)
+			if err := reconciler.Cleanup(ctx, hostgroup.RuleName(g.machineDeploymentName), hostgroup.VMGroupName(g.machineDeploymentName)); err != nil {
+				client.Logout(ctx)
+				return err
+			}
+		}
+
+		client.Logout(ctx)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package worker
+
+import (
+	apisvsphere "github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere"
+)
+
+// staticIPAMSpec renders the "ipamPool" machine class configuration for a
+// worker pool that opts into static IP addressing. A single MachineClass
+// backs every replica of a (pool, zone) combination, so this controller
+// cannot resolve one concrete lease up front without colliding replicas on
+// the same address; instead it passes the IPAM source configuration
+// through, and the MCM vSphere driver calls pkg/vsphere/ipam.Allocator
+// itself for each VM it creates, using that VM's own name as
+// ipam.AllocationRequest.Owner.
+func staticIPAMSpec(config *apisvsphere.StaticIPAMConfig, network string, mtu int32) map[string]interface{} {
+	spec := map[string]interface{}{
+		"source":  string(config.Source),
+		"network": network,
+		"mtu":     mtu,
+	}
+
+	switch config.Source {
+	case apisvsphere.IPAMSourceInCluster:
+		spec["poolName"] = config.InCluster.PoolName
+	case apisvsphere.IPAMSourceInfoblox:
+		spec["host"] = config.Infoblox.Host
+		spec["networkView"] = config.Infoblox.NetworkView
+		spec["credentialsSecretName"] = config.Infoblox.CredentialsSecretName
+	case apisvsphere.IPAMSourcePhpIPAM:
+		spec["endpoint"] = config.PhpIPAM.Endpoint
+		spec["sectionId"] = config.PhpIPAM.SectionID
+		spec["appId"] = config.PhpIPAM.AppID
+		spec["credentialsSecretName"] = config.PhpIPAM.CredentialsSecretName
+	}
+
+	return spec
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package worker
+
+import (
+	"context"
+
+	apisvsphere "github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere"
+	"github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere/helper"
+	"github.com/gardener/gardener-extension-provider-vsphere/pkg/vsphere"
+	"github.com/gardener/gardener-extension-provider-vsphere/pkg/vsphere/contentlib"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/vapi/rest"
+)
+
+// findMachineImage resolves the template VM backing the given machine
+// image name/version for the vCenter/datacenter of zoneConfig. If the
+// configured image already has a Path, that is returned directly;
+// otherwise the image is resolved (and imported on cache-miss) from its
+// configured Content Library.
+func (w *workerDelegate) findMachineImage(ctx context.Context, credentials *vsphere.Credentials, region *apisvsphere.Region, zoneConfig apisvsphere.ZoneConfig, name, version string) (string, string, error) {
+	imageVersion, err := helper.FindMachineImageVersion(w.cloudProfileConfig, name, version)
+	if err != nil {
+		return "", "", err
+	}
+
+	if imageVersion.Path != "" {
+		return imageVersion.Path, imageVersion.GuestID, nil
+	}
+
+	vcenter, err := helper.FindVCenter(region, zoneConfig.VCenter)
+	if err != nil {
+		return "", "", err
+	}
+
+	binding, err := credentials.ForVCenter(vcenter.Name)
+	if err != nil {
+		return "", "", err
+	}
+
+	client, err := vsphere.NewClient(ctx, vcenter.Host, binding.Username, binding.Password, vcenter.InsecureSSL)
+	if err != nil {
+		return "", "", err
+	}
+	defer client.Logout(ctx)
+
+	restClient := rest.NewClient(client.Client)
+	if err := restClient.Login(ctx, client.Client.URL().User); err != nil {
+		return "", "", err
+	}
+
+	resolver := contentlib.NewResolver(find.NewFinder(client.Client, true), restClient)
+	return resolver.Resolve(ctx, *imageVersion, name, zoneConfig)
+}
+
+// appendMachineImage adds image to machineImages unless an entry with the
+// same name, version and path is already present.
+func appendMachineImage(machineImages []apisvsphere.MachineImage, image apisvsphere.MachineImage) []apisvsphere.MachineImage {
+	for _, existing := range machineImages {
+		if existing.Name == image.Name && existing.Version == image.Version && existing.Path == image.Path {
+			return machineImages
+		}
+	}
+	return append(machineImages, image)
+}
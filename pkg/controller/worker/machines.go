@@ -18,13 +18,16 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"strconv"
 
 	apisvsphere "github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere"
 	"github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere/helper"
+	machinepoolcontroller "github.com/gardener/gardener-extension-provider-vsphere/pkg/controller/machinepool"
 	"github.com/gardener/gardener-extension-provider-vsphere/pkg/vsphere"
+	"github.com/gardener/gardener-extension-provider-vsphere/pkg/vsphere/machinepool"
 
 	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
 	"github.com/gardener/gardener/extensions/pkg/controller/worker"
@@ -52,7 +55,33 @@ func (w *workerDelegate) DeployMachineClasses(ctx context.Context) error {
 			return err
 		}
 	}
-	return w.seedChartApplier.Apply(ctx, filepath.Join(vsphere.InternalChartsPath, "machineclass"), w.worker.Namespace, "machineclass", kubernetes.Values(map[string]interface{}{"machineClasses": w.machineClasses}))
+	if err := w.seedChartApplier.Apply(ctx, filepath.Join(vsphere.InternalChartsPath, "machineclass"), w.worker.Namespace, "machineclass", kubernetes.Values(map[string]interface{}{"machineClasses": w.machineClasses})); err != nil {
+		return err
+	}
+
+	region := helper.FindRegion(w.cluster.Shoot.Spec.Region, w.cloudProfileConfig)
+	if region == nil {
+		return fmt.Errorf("region %q not found", w.cluster.Shoot.Spec.Region)
+	}
+	return w.reconcileHostGroups(ctx, region, w.zoneHostGroups)
+}
+
+// CleanupHostGroupResources removes the DRS VM groups and VM-Host affinity
+// rules that were reconciled for this worker's zones. Nothing in this
+// extension calls it: worker deletion does not remove these groups and
+// rules.
+func (w *workerDelegate) CleanupHostGroupResources(ctx context.Context) error {
+	if w.machineDeployments == nil {
+		if err := w.generateMachineConfig(ctx); err != nil {
+			return err
+		}
+	}
+
+	region := helper.FindRegion(w.cluster.Shoot.Spec.Region, w.cloudProfileConfig)
+	if region == nil {
+		return fmt.Errorf("region %q not found", w.cluster.Shoot.Spec.Region)
+	}
+	return w.cleanupHostGroups(ctx, region, w.zoneHostGroups)
 }
 
 // GenerateMachineDeployments generates the configuration for the desired machine deployments.
@@ -65,27 +94,37 @@ func (w *workerDelegate) GenerateMachineDeployments(ctx context.Context) (worker
 	return w.machineDeployments, nil
 }
 
-func (w *workerDelegate) generateMachineClassSecretData(ctx context.Context) (map[string][]byte, error) {
+// extractCredentials fetches the worker's Secret and extracts its vSphere
+// credentials. Callers that need credentials for more than one vCenter
+// should call this once and reuse the result, rather than fetching the
+// Secret again per vCenter.
+func (w *workerDelegate) extractCredentials(ctx context.Context) (*vsphere.Credentials, error) {
 	secret, err := extensionscontroller.GetSecretByReference(ctx, w.Client(), &w.worker.Spec.SecretRef)
 	if err != nil {
 		return nil, err
 	}
+	return vsphere.ExtractCredentials(secret)
+}
 
-	credentials, err := vsphere.ExtractCredentials(secret)
+// generateMachineClassSecretData builds the machine class secret data for
+// the given vCenter, selecting its host and credentials from the already
+// extracted worker credentials (see vsphere.ExtractCredentials).
+func generateMachineClassSecretData(credentials *vsphere.Credentials, region *apisvsphere.Region, vcenterName string) (map[string][]byte, error) {
+	vcenter, err := helper.FindVCenter(region, vcenterName)
 	if err != nil {
 		return nil, err
 	}
 
-	region := helper.FindRegion(w.cluster.Shoot.Spec.Region, w.cloudProfileConfig)
-	if region == nil {
-		return nil, fmt.Errorf("region %q not found", w.cluster.Shoot.Spec.Region)
+	binding, err := credentials.ForVCenter(vcenter.Name)
+	if err != nil {
+		return nil, err
 	}
 
 	return map[string][]byte{
-		vsphere.Host:        []byte(region.VsphereHost),
-		vsphere.Username:    []byte(credentials.VsphereMCM().Username),
-		vsphere.Password:    []byte(credentials.VsphereMCM().Password),
-		vsphere.InsecureSSL: []byte(strconv.FormatBool(region.VsphereInsecureSSL)),
+		vsphere.Host:        []byte(vcenter.Host),
+		vsphere.Username:    []byte(binding.Username),
+		vsphere.Password:    []byte(binding.Password),
+		vsphere.InsecureSSL: []byte(strconv.FormatBool(vcenter.InsecureSSL)),
 	}, nil
 }
 
@@ -94,11 +133,12 @@ func (w *workerDelegate) generateMachineConfig(ctx context.Context) error {
 		machineDeployments = worker.MachineDeployments{}
 		machineClasses     []map[string]interface{}
 		machineImages      []apisvsphere.MachineImage
+		zoneHostGroups     []zoneHostGroup
 	)
 
-	machineClassSecretData, err := w.generateMachineClassSecretData(ctx)
-	if err != nil {
-		return err
+	region := helper.FindRegion(w.cluster.Shoot.Spec.Region, w.cloudProfileConfig)
+	if region == nil {
+		return fmt.Errorf("region %q not found", w.cluster.Shoot.Spec.Region)
 	}
 
 	infrastructureStatus, err := helper.GetInfrastructureStatus(w.worker.Namespace, w.worker.Spec.InfrastructureProviderStatus)
@@ -113,6 +153,11 @@ func (w *workerDelegate) generateMachineConfig(ctx context.Context) error {
 		return fmt.Errorf("missing sshPublicKey for infrastructure")
 	}
 
+	credentials, err := w.extractCredentials(ctx)
+	if err != nil {
+		return err
+	}
+
 	for _, pool := range w.worker.Spec.Pools {
 		zoneLen := int32(len(pool.Zones))
 
@@ -121,16 +166,10 @@ func (w *workerDelegate) generateMachineConfig(ctx context.Context) error {
 			return err
 		}
 
-		machineImagePath, machineImageGuestID, err := w.findMachineImage(pool.MachineImage.Name, pool.MachineImage.Version)
+		workerConfig, err := helper.DecodeWorkerConfig(pool.ProviderConfig)
 		if err != nil {
 			return err
 		}
-		machineImages = appendMachineImage(machineImages, apisvsphere.MachineImage{
-			Name:    pool.MachineImage.Name,
-			Version: pool.MachineImage.Version,
-			Path:    machineImagePath,
-			GuestID: machineImageGuestID,
-		})
 
 		numCpus, memoryInMB, systenDiskSizeInGB, err := w.extractMachineValues(pool.MachineType)
 		if err != nil {
@@ -143,6 +182,27 @@ func (w *workerDelegate) generateMachineConfig(ctx context.Context) error {
 			if !ok {
 				return fmt.Errorf("zoneConfig not found for zone %s", zone)
 			}
+
+			machineClassSecretData, err := generateMachineClassSecretData(credentials, region, zoneConfig.VCenter)
+			if err != nil {
+				return err
+			}
+
+			machineImagePath, machineImageGuestID, err := w.findMachineImage(ctx, credentials, region, zoneConfig, pool.MachineImage.Name, pool.MachineImage.Version)
+			if err != nil {
+				return err
+			}
+			machineImage := apisvsphere.MachineImage{
+				Name:    pool.MachineImage.Name,
+				Version: pool.MachineImage.Version,
+				Path:    machineImagePath,
+				GuestID: machineImageGuestID,
+			}
+			if imageVersion, err := helper.FindMachineImageVersion(w.cloudProfileConfig, pool.MachineImage.Name, pool.MachineImage.Version); err == nil && imageVersion.Path == "" {
+				machineImage.ContentLibrary = imageVersion.ContentLibrary
+			}
+			machineImages = appendMachineImage(machineImages, machineImage)
+
 			machineClassSpec := map[string]interface{}{
 				"region":     infrastructureStatus.VsphereConfig.Region,
 				"sshKeys":    []string{string(w.worker.Spec.SSHPublicKey)},
@@ -175,12 +235,45 @@ func (w *workerDelegate) generateMachineConfig(ctx context.Context) error {
 			addOptional("datastore", zoneConfig.Datastore)
 			addOptional("datastoreCluster", zoneConfig.DatastoreCluster)
 			addOptional("switchUuid", zoneConfig.SwitchUUID)
+			addOptional("hostGroup", zoneConfig.HostGroup)
 
 			var (
 				deploymentName = fmt.Sprintf("%s-%s-z%d", w.worker.Namespace, pool.Name, zoneIndex+1)
 				className      = fmt.Sprintf("%s-%s", deploymentName, workerPoolHash)
+				annotations    = pool.Annotations
 			)
 
+			if workerConfig.MachinePool != nil {
+				folderParent := infrastructureStatus.VsphereConfig.Folder
+				if workerConfig.MachinePool.FolderTemplate != nil && workerConfig.MachinePool.FolderTemplate.Parent != "" {
+					folderParent = workerConfig.MachinePool.FolderTemplate.Parent
+				}
+				folderName, err := machinepool.FolderName(machinepool.PoolIdentity{Namespace: w.worker.Namespace, Pool: pool.Name}, workerConfig.MachinePool.FolderTemplate)
+				if err != nil {
+					return err
+				}
+				folderPath := folderName
+				if folderParent != "" {
+					folderPath = folderParent + "/" + folderName
+				}
+				addOptional("folder", folderPath)
+
+				state := machinepoolcontroller.State{
+					Pool:               pool.Name,
+					VCenter:            zoneConfig.VCenter,
+					ComputeCluster:     zoneConfig.ComputeCluster,
+					FolderParent:       folderParent,
+					FolderName:         folderName,
+					MinReadySeconds:    workerConfig.MachinePool.MinReadySeconds,
+					AntiAffinityPolicy: workerConfig.MachinePool.AntiAffinityPolicy,
+				}
+				rawState, err := json.Marshal(state)
+				if err != nil {
+					return err
+				}
+				annotations = extensionscontroller.MergeStringMaps(annotations, map[string]string{machinepool.Annotation: string(rawState)})
+			}
+
 			machineDeployments = append(machineDeployments, worker.MachineDeployment{
 				Name:           deploymentName,
 				ClassName:      className,
@@ -190,16 +283,29 @@ func (w *workerDelegate) generateMachineConfig(ctx context.Context) error {
 				MaxSurge:       worker.DistributePositiveIntOrPercent(zoneIdx, pool.MaxSurge, zoneLen, pool.Maximum),
 				MaxUnavailable: worker.DistributePositiveIntOrPercent(zoneIdx, pool.MaxUnavailable, zoneLen, pool.Minimum),
 				Labels:         pool.Labels,
-				Annotations:    pool.Annotations,
+				Annotations:    annotations,
 				Taints:         pool.Taints,
 			})
 
+			if zoneConfig.HostGroup != "" {
+				zoneHostGroups = append(zoneHostGroups, zoneHostGroup{machineDeploymentName: deploymentName, zoneConfig: zoneConfig})
+			}
+
 			machineClassSpec["name"] = className
 			secretMap := machineClassSpec["secret"].(map[string]interface{})
 			for k, v := range machineClassSecretData {
 				secretMap[k] = string(v)
 			}
 
+			if workerConfig.StaticIPAM != nil {
+				// A MachineClass backs every replica of this (pool, zone), so
+				// the static address itself cannot be resolved here: it must
+				// be allocated per VM, by the MCM vSphere driver, at the
+				// point where it creates each individual VM. We only pass the
+				// IPAM source configuration through; see staticIPAMSpec.
+				machineClassSpec["ipamPool"] = staticIPAMSpec(workerConfig.StaticIPAM, *infrastructureStatus.NSXTInfraState.SegmentName, zoneConfig.MTU)
+			}
+
 			machineClasses = append(machineClasses, machineClassSpec)
 		}
 	}
@@ -207,6 +313,7 @@ func (w *workerDelegate) generateMachineConfig(ctx context.Context) error {
 	w.machineDeployments = machineDeployments
 	w.machineClasses = machineClasses
 	w.machineImages = machineImages
+	w.zoneHostGroups = zoneHostGroups
 
 	return nil
 }
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package vsphere
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/vmware/govmomi"
+)
+
+// NewClient logs into the vCenter at host with the given credentials and
+// returns a ready-to-use govmomi client. Callers are responsible for calling
+// Logout on the returned client's session manager once done.
+func NewClient(ctx context.Context, host, username, password string, insecureSSL bool) (*govmomi.Client, error) {
+	u, err := url.Parse(fmt.Sprintf("https://%s/sdk", host))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse vCenter URL for host %q: %v", host, err)
+	}
+	u.User = url.UserPassword(username, password)
+
+	client, err := govmomi.NewClient(ctx, u, insecureSSL)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to vCenter %q: %v", host, err)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+// Package cloudinit renders the cloud-init NoCloud seed content that the
+// MCM vSphere driver attaches to a machine that requests static IP
+// addressing instead of relying on DHCP. The NoCloud datasource reads
+// meta-data and network-config as separate seed files, so this package
+// renders them as two distinct values rather than nesting one inside the
+// other.
+package cloudinit
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/gardener/gardener-extension-provider-vsphere/pkg/vsphere/ipam"
+
+	"gopkg.in/yaml.v2"
+)
+
+const defaultMTU = 1500
+
+// networkConfigV2 mirrors the subset of cloud-init's network-config version
+// 2 schema that is needed to configure a single static interface.
+type networkConfigV2 struct {
+	Version   int                        `yaml:"version"`
+	Ethernets map[string]ethernetConfigV2 `yaml:"ethernets"`
+}
+
+type ethernetConfigV2 struct {
+	Addresses   []string `yaml:"addresses"`
+	Gateway4    string   `yaml:"gateway4,omitempty"`
+	Nameservers struct {
+		Addresses []string `yaml:"addresses,omitempty"`
+	} `yaml:"nameservers,omitempty"`
+	MTU int `yaml:"mtu,omitempty"`
+}
+
+// BuildNoCloudMetaData renders a base64-encoded cloud-init NoCloud
+// "meta-data" seed document for the machine identified by instanceID and
+// hostname. It carries no network configuration: that is a separate seed
+// file, rendered by BuildNoCloudNetworkConfig.
+func BuildNoCloudMetaData(instanceID, hostname string) string {
+	metaData := fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", instanceID, hostname)
+	return base64.StdEncoding.EncodeToString([]byte(metaData))
+}
+
+// BuildNoCloudNetworkConfig renders a base64-encoded cloud-init
+// network-config version 2 document for the given lease, for the named
+// interface (the vSphere guest customization convention is "ens192" for
+// the first NIC on Linux VMs). The NoCloud datasource consumes this as its
+// own seed content, separate from meta-data.
+func BuildNoCloudNetworkConfig(interfaceName string, lease *ipam.Lease, mtu int32) (string, error) {
+	if lease.IPAddress == "" {
+		return "", fmt.Errorf("lease has no IP address")
+	}
+	if mtu <= 0 {
+		mtu = defaultMTU
+	}
+
+	eth := ethernetConfigV2{
+		Addresses: []string{fmt.Sprintf("%s/%d", lease.IPAddress, lease.PrefixLength)},
+		Gateway4:  lease.Gateway,
+		MTU:       int(mtu),
+	}
+	eth.Nameservers.Addresses = lease.Nameservers
+
+	networkConfig := networkConfigV2{
+		Version:   2,
+		Ethernets: map[string]ethernetConfigV2{interfaceName: eth},
+	}
+
+	networkConfigYAML, err := yaml.Marshal(networkConfig)
+	if err != nil {
+		return "", fmt.Errorf("could not render network-config: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(networkConfigYAML), nil
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package cloudinit
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/gardener/gardener-extension-provider-vsphere/pkg/vsphere/ipam"
+)
+
+func TestBuildNoCloudMetaData(t *testing.T) {
+	raw, err := base64.StdEncoding.DecodeString(BuildNoCloudMetaData("i-1234", "worker-1"))
+	if err != nil {
+		t.Fatalf("meta-data is not valid base64: %v", err)
+	}
+
+	metaData := string(raw)
+	if !strings.Contains(metaData, "instance-id: i-1234") {
+		t.Errorf("meta-data missing instance-id: %q", metaData)
+	}
+	if !strings.Contains(metaData, "local-hostname: worker-1") {
+		t.Errorf("meta-data missing local-hostname: %q", metaData)
+	}
+	if strings.Contains(metaData, "network-config") {
+		t.Errorf("meta-data must not embed network-config, got %q", metaData)
+	}
+}
+
+func TestBuildNoCloudNetworkConfig(t *testing.T) {
+	lease := &ipam.Lease{
+		IPAddress:    "10.0.0.5",
+		PrefixLength: 24,
+		Gateway:      "10.0.0.1",
+		Nameservers:  []string{"10.0.0.2"},
+	}
+
+	encoded, err := BuildNoCloudNetworkConfig("ens192", lease, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("network-config is not valid base64: %v", err)
+	}
+
+	networkConfig := string(raw)
+	if strings.HasPrefix(networkConfig, "network-config:") {
+		t.Errorf("network-config must not be nested under a network-config key, got %q", networkConfig)
+	}
+	if !strings.Contains(networkConfig, "10.0.0.5/24") {
+		t.Errorf("network-config missing address, got %q", networkConfig)
+	}
+	if !strings.Contains(networkConfig, "mtu: 1500") {
+		t.Errorf("network-config missing default MTU, got %q", networkConfig)
+	}
+}
+
+func TestBuildNoCloudNetworkConfigRequiresAddress(t *testing.T) {
+	if _, err := BuildNoCloudNetworkConfig("ens192", &ipam.Lease{}, 0); err == nil {
+		t.Fatal("expected error for lease without an IP address")
+	}
+}
@@ -0,0 +1,278 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+// Package contentlib resolves machine images from a vSphere Content
+// Library, importing them from an OVA/OVF URL on cache-miss, so that a
+// fresh Gardener install on a new vCenter doesn't require operators to
+// manually upload templates.
+package contentlib
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	apisvsphere "github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/vapi/library"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/vcenter"
+)
+
+// Resolver resolves a configured machine image to the inventory path of a
+// template VM that machines can be cloned from.
+type Resolver interface {
+	// Resolve returns the inventory path and guest OS identifier for the
+	// given image in zoneConfig's datacenter/compute cluster, importing it
+	// from image.OVAURL into image.ContentLibrary first if it is not yet
+	// present there.
+	Resolve(ctx context.Context, image apisvsphere.MachineImageVersion, name string, zoneConfig apisvsphere.ZoneConfig) (templatePath, guestID string, err error)
+}
+
+// resolver is the default Resolver implementation, backed by a live
+// govmomi/vapi session against a single vCenter.
+type resolver struct {
+	finder         *find.Finder
+	libraryManager *library.Manager
+}
+
+// NewResolver creates a Resolver using finder to look up existing templates
+// and the datacenter, and restClient to talk to the Content Library and
+// VM-deploy vAPI endpoints.
+func NewResolver(finder *find.Finder, restClient *rest.Client) Resolver {
+	return &resolver{
+		finder:         finder,
+		libraryManager: library.NewManager(restClient),
+	}
+}
+
+func (r *resolver) Resolve(ctx context.Context, image apisvsphere.MachineImageVersion, name string, zoneConfig apisvsphere.ZoneConfig) (string, string, error) {
+	if image.Path != "" {
+		return image.Path, image.GuestID, nil
+	}
+	if image.ContentLibrary == "" {
+		return "", "", fmt.Errorf("machine image %q@%q has neither a path nor a contentLibrary configured", name, image.Version)
+	}
+
+	item, err := r.findLibraryItem(ctx, image.ContentLibrary, name, image.Version)
+	if err != nil {
+		return "", "", err
+	}
+
+	if item == nil {
+		if image.OVAURL == "" {
+			return "", "", fmt.Errorf("machine image %q@%q not found in content library %q and no ovaUrl configured to import it", name, image.Version, image.ContentLibrary)
+		}
+		ovaPath, cleanup, err := r.downloadOVA(ctx, image.OVAURL, image.SHA256)
+		if err != nil {
+			return "", "", err
+		}
+		defer cleanup()
+
+		item, err = r.importOVA(ctx, image, name, ovaPath)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	templatePath, err := r.deployTemplate(ctx, item, name, image.Version, zoneConfig)
+	if err != nil {
+		return "", "", err
+	}
+
+	return templatePath, image.GuestID, nil
+}
+
+func (r *resolver) findLibraryItem(ctx context.Context, libraryName, name, version string) (*library.Item, error) {
+	lib, err := r.libraryManager.GetLibraryByName(ctx, libraryName)
+	if err != nil {
+		return nil, fmt.Errorf("could not find content library %q: %v", libraryName, err)
+	}
+
+	itemName := itemName(name, version)
+	items, err := r.libraryManager.FindLibraryItems(ctx, library.FindItem{LibraryID: lib.ID, Name: itemName})
+	if err != nil {
+		return nil, fmt.Errorf("could not list items of content library %q: %v", libraryName, err)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	item, err := r.libraryManager.GetLibraryItem(ctx, items[0])
+	if err != nil {
+		return nil, fmt.Errorf("could not read content library item %q: %v", itemName, err)
+	}
+	return item, nil
+}
+
+func (r *resolver) importOVA(ctx context.Context, image apisvsphere.MachineImageVersion, name, ovaPath string) (*library.Item, error) {
+	lib, err := r.libraryManager.GetLibraryByName(ctx, image.ContentLibrary)
+	if err != nil {
+		return nil, fmt.Errorf("could not find content library %q: %v", image.ContentLibrary, err)
+	}
+
+	item := library.Item{
+		Name:      itemName(name, image.Version),
+		Type:      library.ItemTypeOVF,
+		LibraryID: lib.ID,
+	}
+
+	sessionID, err := r.libraryManager.CreateLibraryItemUpdateSession(ctx, library.SessionUpload{Library: item})
+	if err != nil {
+		return nil, fmt.Errorf("could not create import session for %q: %v", item.Name, err)
+	}
+
+	if err := r.libraryManager.UploadLibraryItemFile(ctx, sessionID, "ova", ovaPath); err != nil {
+		return nil, fmt.Errorf("could not import %q from %q: %v", item.Name, image.OVAURL, err)
+	}
+	if err := r.libraryManager.CompleteLibraryItemUpdateSession(ctx, sessionID); err != nil {
+		return nil, fmt.Errorf("could not complete import of %q: %v", item.Name, err)
+	}
+
+	return r.findLibraryItem(ctx, image.ContentLibrary, name, image.Version)
+}
+
+func (r *resolver) deployTemplate(ctx context.Context, item *library.Item, name, version string, zoneConfig apisvsphere.ZoneConfig) (string, error) {
+	dc, err := r.finder.Datacenter(ctx, zoneConfig.Datacenter)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve datacenter %q: %v", zoneConfig.Datacenter, err)
+	}
+	folders, err := dc.Folders(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve folders of datacenter %q: %v", zoneConfig.Datacenter, err)
+	}
+
+	templateVMName := itemName(name, version)
+	if existing, err := r.finder.VirtualMachine(ctx, templateVMName); err == nil {
+		return existing.InventoryPath, nil
+	}
+
+	resourcePoolID, err := r.resolveResourcePool(ctx, zoneConfig)
+	if err != nil {
+		return "", err
+	}
+
+	target := vcenter.Target{
+		ResourcePoolID: resourcePoolID,
+		FolderID:       folders.VmFolder.Reference().Value,
+	}
+	if zoneConfig.HostSystem != "" {
+		host, err := r.finder.HostSystem(ctx, zoneConfig.HostSystem)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve host system %q: %v", zoneConfig.HostSystem, err)
+		}
+		target.HostID = host.Reference().Value
+	}
+
+	deployment := vcenter.Deploy{
+		DeploymentSpec: vcenter.DeploymentSpec{
+			Name:               templateVMName,
+			DefaultDatastoreID: "",
+		},
+		Target: target,
+	}
+
+	restClient := r.libraryManager.Client
+	if _, err := vcenter.NewManager(restClient).DeployLibraryItem(ctx, item.ID, deployment); err != nil {
+		return "", fmt.Errorf("could not deploy content library item %q: %v", item.Name, err)
+	}
+
+	vm, err := r.finder.VirtualMachine(ctx, templateVMName)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve inventory path of deployed VM for item %q: %v", item.Name, err)
+	}
+
+	return vm.InventoryPath, nil
+}
+
+// resolveResourcePool resolves the managed object ID of the resource pool
+// that a deployed template VM is placed in: the zone's explicit
+// ResourcePool if set, otherwise the compute cluster's default resource
+// pool.
+func (r *resolver) resolveResourcePool(ctx context.Context, zoneConfig apisvsphere.ZoneConfig) (string, error) {
+	if zoneConfig.ResourcePool != "" {
+		pool, err := r.finder.ResourcePool(ctx, zoneConfig.ResourcePool)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve resource pool %q: %v", zoneConfig.ResourcePool, err)
+		}
+		return pool.Reference().Value, nil
+	}
+	if zoneConfig.ComputeCluster == "" {
+		return "", fmt.Errorf("zone has neither resourcePool nor computeCluster configured to deploy the template into")
+	}
+
+	cluster, err := r.finder.ClusterComputeResource(ctx, zoneConfig.ComputeCluster)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve compute cluster %q: %v", zoneConfig.ComputeCluster, err)
+	}
+	pool, err := cluster.ResourcePool(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve default resource pool of compute cluster %q: %v", zoneConfig.ComputeCluster, err)
+	}
+	return pool.Reference().Value, nil
+}
+
+// downloadOVA fetches url once into a temp file, verifying its checksum
+// against expectedSHA256 (a no-op if empty) while streaming it to disk, so
+// the caller can import the same bytes into the content library without a
+// second, potentially multi-GB, download. The caller must invoke the
+// returned cleanup function once done with the file.
+func (r *resolver) downloadOVA(ctx context.Context, url, expectedSHA256 string) (path string, cleanup func(), err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not fetch %q: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	tmp, err := os.CreateTemp("", "contentlib-ova-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("could not create temp file for %q: %v", url, err)
+	}
+	cleanup = func() { os.Remove(tmp.Name()) }
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), resp.Body); err != nil {
+		tmp.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("could not read %q: %v", url, err)
+	}
+	if err := tmp.Close(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("could not write temp file for %q: %v", url, err)
+	}
+
+	if expectedSHA256 != "" {
+		if actual := hex.EncodeToString(hash.Sum(nil)); actual != expectedSHA256 {
+			cleanup()
+			return "", nil, fmt.Errorf("checksum mismatch for %q: expected %q, got %q", url, expectedSHA256, actual)
+		}
+	}
+
+	return tmp.Name(), cleanup, nil
+}
+
+func itemName(name, version string) string {
+	return fmt.Sprintf("%s-%s", name, version)
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package contentlib
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestDownloadOVA(t *testing.T) {
+	const (
+		content    = "some-ova-content"
+		content256 = "8781184324b2ff393470ee451580f07d5f8010984111422e54f234a27ac9c63c"
+		wrong256   = "deadbeef"
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	r := &resolver{}
+
+	path, cleanup, err := r.downloadOVA(context.Background(), server.URL, "")
+	if err != nil {
+		t.Fatalf("expected no error when expectedSHA256 is empty, got %v", err)
+	}
+	assertFileContent(t, path, content)
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected cleanup to remove %q, got err=%v", path, err)
+	}
+
+	path, cleanup, err = r.downloadOVA(context.Background(), server.URL, content256)
+	if err != nil {
+		t.Fatalf("expected checksum to match, got %v", err)
+	}
+	assertFileContent(t, path, content)
+	cleanup()
+
+	if _, _, err := r.downloadOVA(context.Background(), server.URL, wrong256); err == nil {
+		t.Fatal("expected checksum mismatch error, got nil")
+	}
+}
+
+func assertFileContent(t *testing.T, path, want string) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read downloaded file %q: %v", path, err)
+	}
+	if string(got) != want {
+		t.Errorf("downloaded file content = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+// Package hostgroup reconciles DRS VM-Host affinity rules that pin the VMs
+// of a MachineDeployment to a pre-existing host group, implementing
+// host-group based zonal topology in a single vSphere cluster.
+package hostgroup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// RuleName returns the name of the DRS VM-Host affinity rule reconciled for
+// the given MachineDeployment.
+func RuleName(machineDeploymentName string) string {
+	return "mcm-" + machineDeploymentName
+}
+
+// VMGroupName returns the name of the DRS VM group reconciled for the given
+// MachineDeployment.
+func VMGroupName(machineDeploymentName string) string {
+	return "mcm-" + machineDeploymentName + "-vms"
+}
+
+// Reconciler reconciles the VM group and VM-Host affinity rule for a single
+// compute cluster.
+type Reconciler struct {
+	Cluster *object.ClusterComputeResource
+}
+
+// NewReconciler creates a Reconciler for the given compute cluster.
+func NewReconciler(cluster *object.ClusterComputeResource) *Reconciler {
+	return &Reconciler{Cluster: cluster}
+}
+
+// EnsureVMGroup ensures that a VM group with the given name exists in the
+// cluster, creating it empty if necessary.
+func (r *Reconciler) EnsureVMGroup(ctx context.Context, groupName string) error {
+	existing, err := r.clusterConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range existing.Group {
+		if info, ok := group.(*types.ClusterVmGroup); ok && info.Name == groupName {
+			return nil
+		}
+	}
+
+	spec := &types.ClusterConfigSpecEx{
+		GroupSpec: []types.ClusterGroupSpec{
+			{
+				ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationAdd},
+				Info: &types.ClusterVmGroup{
+					ClusterGroupInfo: types.ClusterGroupInfo{Name: groupName},
+				},
+			},
+		},
+	}
+	return r.reconfigure(ctx, spec)
+}
+
+// AddVM adds the given VM to the named VM group if it is not already a
+// member.
+func (r *Reconciler) AddVM(ctx context.Context, groupName string, vm types.ManagedObjectReference) error {
+	existing, err := r.clusterConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	var members []types.ManagedObjectReference
+	for _, group := range existing.Group {
+		info, ok := group.(*types.ClusterVmGroup)
+		if !ok || info.Name != groupName {
+			continue
+		}
+		for _, member := range info.Vm {
+			if member == vm {
+				return nil
+			}
+		}
+		members = info.Vm
+	}
+
+	spec := &types.ClusterConfigSpecEx{
+		GroupSpec: []types.ClusterGroupSpec{
+			{
+				ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationEdit},
+				Info: &types.ClusterVmGroup{
+					ClusterGroupInfo: types.ClusterGroupInfo{Name: groupName},
+					Vm:               append(append([]types.ManagedObjectReference{}, members...), vm),
+				},
+			},
+		},
+	}
+	return r.reconfigure(ctx, spec)
+}
+
+// EnsureHostRule ensures that a "must run on" VM-Host affinity rule exists
+// linking vmGroupName to hostGroupName.
+func (r *Reconciler) EnsureHostRule(ctx context.Context, ruleName, vmGroupName, hostGroupName string) error {
+	existing, err := r.clusterConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	operation := types.ArrayUpdateOperationAdd
+	var key int32
+	for _, rule := range existing.Rule {
+		if info, ok := rule.(*types.ClusterVmHostRuleInfo); ok && info.Name == ruleName {
+			operation = types.ArrayUpdateOperationEdit
+			key = info.Key
+			break
+		}
+	}
+
+	spec := &types.ClusterConfigSpecEx{
+		RulesSpec: []types.ClusterRuleSpec{
+			{
+				ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: operation},
+				Info: &types.ClusterVmHostRuleInfo{
+					ClusterRuleInfo: types.ClusterRuleInfo{
+						Key:       key,
+						Name:      ruleName,
+						Enabled:   types.NewBool(true),
+						Mandatory: types.NewBool(true),
+					},
+					VmGroupName:         vmGroupName,
+					AffineHostGroupName: hostGroupName,
+				},
+			},
+		},
+	}
+	return r.reconfigure(ctx, spec)
+}
+
+// Cleanup removes the VM-Host affinity rule and VM group reconciled for a
+// MachineDeployment. It is a no-op if they no longer exist.
+func (r *Reconciler) Cleanup(ctx context.Context, ruleName, vmGroupName string) error {
+	existing, err := r.clusterConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	spec := &types.ClusterConfigSpecEx{}
+	for _, rule := range existing.Rule {
+		if info, ok := rule.(*types.ClusterVmHostRuleInfo); ok && info.Name == ruleName {
+			spec.RulesSpec = append(spec.RulesSpec, types.ClusterRuleSpec{
+				ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationRemove, RemoveKey: info.Key},
+			})
+		}
+	}
+	for _, group := range existing.Group {
+		if info, ok := group.(*types.ClusterVmGroup); ok && info.Name == vmGroupName {
+			spec.GroupSpec = append(spec.GroupSpec, types.ClusterGroupSpec{
+				ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationRemove, RemoveKey: info.Name},
+			})
+		}
+	}
+
+	if len(spec.RulesSpec) == 0 && len(spec.GroupSpec) == 0 {
+		return nil
+	}
+	return r.reconfigure(ctx, spec)
+}
+
+func (r *Reconciler) clusterConfig(ctx context.Context) (*types.ClusterConfigInfoEx, error) {
+	config, err := r.Cluster.Configuration(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read configuration of cluster %q: %v", r.Cluster.Name(), err)
+	}
+	return config, nil
+}
+
+func (r *Reconciler) reconfigure(ctx context.Context, spec *types.ClusterConfigSpecEx) error {
+	task, err := r.Cluster.Reconfigure(ctx, spec, true)
+	if err != nil {
+		return fmt.Errorf("could not reconfigure cluster %q: %v", r.Cluster.Name(), err)
+	}
+	if err := task.Wait(ctx); err != nil {
+		return fmt.Errorf("reconfiguration of cluster %q failed: %v", r.Cluster.Name(), err)
+	}
+	return nil
+}
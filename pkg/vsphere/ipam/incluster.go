@@ -0,0 +1,169 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package ipam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// inClusterAllocator allocates addresses from a CIDR declared on a
+// ConfigMap, tracking leases in the ConfigMap's data so allocation survives
+// controller restarts.
+type inClusterAllocator struct {
+	client    client.Client
+	namespace string
+	poolName  string
+}
+
+// NewInClusterAllocator creates an Allocator backed by the IP pool
+// ConfigMap "poolName" in namespace. The ConfigMap is expected to carry
+// "cidr", "gateway" and "nameservers" (comma-separated) keys; allocated
+// leases are tracked under its "allocations" key as a JSON map of owner to
+// IP address.
+func NewInClusterAllocator(c client.Client, namespace, poolName string) Allocator {
+	return &inClusterAllocator{client: c, namespace: namespace, poolName: poolName}
+}
+
+func (a *inClusterAllocator) Allocate(ctx context.Context, req AllocationRequest) (*Lease, error) {
+	pool := &corev1.ConfigMap{}
+	if err := a.client.Get(ctx, types.NamespacedName{Namespace: a.namespace, Name: a.poolName}, pool); err != nil {
+		return nil, fmt.Errorf("could not read IP pool %q: %v", a.poolName, err)
+	}
+
+	_, ipNet, err := net.ParseCIDR(pool.Data["cidr"])
+	if err != nil {
+		return nil, fmt.Errorf("IP pool %q has invalid cidr %q: %v", a.poolName, pool.Data["cidr"], err)
+	}
+	prefixLength, _ := ipNet.Mask.Size()
+
+	allocations := map[string]string{}
+	if raw, ok := pool.Data["allocations"]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &allocations); err != nil {
+			return nil, fmt.Errorf("IP pool %q has invalid allocations data: %v", a.poolName, err)
+		}
+	}
+
+	if ip, ok := allocations[req.Owner]; ok {
+		return a.toLease(pool, ip, prefixLength), nil
+	}
+
+	used := map[string]bool{}
+	for _, ip := range allocations {
+		used[ip] = true
+	}
+
+	ip, err := firstFreeIP(ipNet, used)
+	if err != nil {
+		return nil, fmt.Errorf("IP pool %q is exhausted: %v", a.poolName, err)
+	}
+
+	allocations[req.Owner] = ip
+	raw, err := json.Marshal(allocations)
+	if err != nil {
+		return nil, err
+	}
+	if pool.Data == nil {
+		pool.Data = map[string]string{}
+	}
+	pool.Data["allocations"] = string(raw)
+
+	if err := a.client.Update(ctx, pool); err != nil {
+		return nil, fmt.Errorf("could not persist allocation in IP pool %q: %v", a.poolName, err)
+	}
+
+	return a.toLease(pool, ip, prefixLength), nil
+}
+
+func (a *inClusterAllocator) Release(ctx context.Context, req AllocationRequest) error {
+	pool := &corev1.ConfigMap{}
+	if err := a.client.Get(ctx, types.NamespacedName{Namespace: a.namespace, Name: a.poolName}, pool); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("could not read IP pool %q: %v", a.poolName, err)
+	}
+
+	allocations := map[string]string{}
+	if raw, ok := pool.Data["allocations"]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &allocations); err != nil {
+			return fmt.Errorf("IP pool %q has invalid allocations data: %v", a.poolName, err)
+		}
+	}
+	if _, ok := allocations[req.Owner]; !ok {
+		return nil
+	}
+	delete(allocations, req.Owner)
+
+	raw, err := json.Marshal(allocations)
+	if err != nil {
+		return err
+	}
+	pool.Data["allocations"] = string(raw)
+
+	return a.client.Update(ctx, pool)
+}
+
+func (a *inClusterAllocator) toLease(pool *corev1.ConfigMap, ip string, prefixLength int) *Lease {
+	var nameservers []string
+	if raw := pool.Data["nameservers"]; raw != "" {
+		nameservers = strings.Split(raw, ",")
+	}
+	return &Lease{
+		IPAddress:    ip,
+		PrefixLength: prefixLength,
+		Gateway:      pool.Data["gateway"],
+		Nameservers:  nameservers,
+	}
+}
+
+func firstFreeIP(ipNet *net.IPNet, used map[string]bool) (string, error) {
+	ip := ipNet.IP.Mask(ipNet.Mask)
+	for ipNet.Contains(ip) {
+		incIP(ip)
+		if !ipNet.Contains(ip) {
+			break
+		}
+		next := append(net.IP(nil), ip...)
+		incIP(next)
+		if !ipNet.Contains(next) {
+			// ip is the network's broadcast address.
+			break
+		}
+		if !used[ip.String()] {
+			return ip.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no free address in %s", ipNet.String())
+}
+
+func incIP(ip net.IP) {
+	for i := len(ip) - 1; i >= 0; i-- {
+		ip[i]++
+		if ip[i] != 0 {
+			break
+		}
+	}
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package ipam
+
+import (
+	"net"
+	"testing"
+)
+
+func TestFirstFreeIP(t *testing.T) {
+	_, ipNet, err := net.ParseCIDR("10.0.0.0/30")
+	if err != nil {
+		t.Fatalf("invalid test CIDR: %v", err)
+	}
+
+	first, err := firstFreeIP(ipNet, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first != "10.0.0.1" {
+		t.Errorf("expected 10.0.0.1, got %s", first)
+	}
+
+	second, err := firstFreeIP(ipNet, map[string]bool{"10.0.0.1": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second != "10.0.0.2" {
+		t.Errorf("expected 10.0.0.2, got %s", second)
+	}
+}
+
+func TestFirstFreeIPExhausted(t *testing.T) {
+	_, ipNet, err := net.ParseCIDR("10.0.0.0/30")
+	if err != nil {
+		t.Fatalf("invalid test CIDR: %v", err)
+	}
+
+	used := map[string]bool{"10.0.0.1": true, "10.0.0.2": true}
+	if _, err := firstFreeIP(ipNet, used); err == nil {
+		t.Fatal("expected an error when the pool is exhausted")
+	}
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package ipam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// infobloxAllocator allocates addresses via the Infoblox WAPI
+// "nextavailableip" function call against a fixed network view.
+type infobloxAllocator struct {
+	httpClient  *http.Client
+	baseURL     string
+	networkView string
+	username    string
+	password    string
+}
+
+// NewInfobloxAllocator creates an Allocator backed by an Infoblox grid
+// master reachable at host, allocating from the given network view.
+func NewInfobloxAllocator(host, networkView, username, password string) Allocator {
+	return &infobloxAllocator{
+		httpClient:  http.DefaultClient,
+		baseURL:     fmt.Sprintf("https://%s/wapi/v2.11", host),
+		networkView: networkView,
+		username:    username,
+		password:    password,
+	}
+}
+
+func (a *infobloxAllocator) Allocate(ctx context.Context, req AllocationRequest) (*Lease, error) {
+	if lease, ok, err := a.existingLease(ctx, req); err != nil {
+		return nil, err
+	} else if ok {
+		return lease, nil
+	}
+
+	prefixLength, gateway, nameservers, err := a.networkParams(ctx, req.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"ipv4addrs": []map[string]interface{}{
+			{
+				"ipv4addr": fmt.Sprintf("func:nextavailableip:%s,%s", req.Network, a.networkView),
+			},
+		},
+		"name":    req.Owner,
+		"comment": "allocated by gardener-extension-provider-vsphere",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		IPv4Addrs []struct {
+			IPv4Addr string `json:"ipv4addr"`
+		} `json:"ipv4addrs"`
+	}
+	if err := a.do(ctx, http.MethodPost, "/record:host", payload, &result); err != nil {
+		return nil, fmt.Errorf("could not allocate address for %q from Infoblox: %v", req.Owner, err)
+	}
+	if len(result.IPv4Addrs) == 0 {
+		return nil, fmt.Errorf("Infoblox did not return an address for %q", req.Owner)
+	}
+
+	return &Lease{
+		IPAddress:    result.IPv4Addrs[0].IPv4Addr,
+		PrefixLength: prefixLength,
+		Gateway:      gateway,
+		Nameservers:  nameservers,
+	}, nil
+}
+
+// existingLease looks up a host record already allocated for req.Owner, so
+// Allocate can be called repeatedly (e.g. on every worker reconcile)
+// without leaking a new address from the pool each time.
+func (a *infobloxAllocator) existingLease(ctx context.Context, req AllocationRequest) (*Lease, bool, error) {
+	var result []struct {
+		IPv4Addrs []struct {
+			IPv4Addr string `json:"ipv4addr"`
+		} `json:"ipv4addrs"`
+	}
+	if err := a.do(ctx, http.MethodGet, fmt.Sprintf("/record:host?name=%s&_return_fields=ipv4addrs", req.Owner), nil, &result); err != nil {
+		return nil, false, fmt.Errorf("could not look up existing Infoblox record for %q: %v", req.Owner, err)
+	}
+	if len(result) == 0 || len(result[0].IPv4Addrs) == 0 {
+		return nil, false, nil
+	}
+
+	prefixLength, gateway, nameservers, err := a.networkParams(ctx, req.Network)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &Lease{
+		IPAddress:    result[0].IPv4Addrs[0].IPv4Addr,
+		PrefixLength: prefixLength,
+		Gateway:      gateway,
+		Nameservers:  nameservers,
+	}, true, nil
+}
+
+// networkParams derives the prefix length of network (a CIDR, e.g.
+// "10.0.0.0/24") and looks up the "routers"/"domain-name-servers" DHCP
+// options configured on the matching Infoblox network object, so leases
+// carry a usable gateway and nameservers alongside the allocated address.
+func (a *infobloxAllocator) networkParams(ctx context.Context, network string) (int, string, []string, error) {
+	_, ipNet, err := net.ParseCIDR(network)
+	if err != nil {
+		return 0, "", nil, fmt.Errorf("invalid Infoblox network %q: %v", network, err)
+	}
+	prefixLength, _ := ipNet.Mask.Size()
+
+	var result []struct {
+		Options []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"options"`
+	}
+	path := fmt.Sprintf("/network?network=%s&network_view=%s&_return_fields=options", network, a.networkView)
+	if err := a.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return 0, "", nil, fmt.Errorf("could not look up Infoblox network %q: %v", network, err)
+	}
+	if len(result) == 0 {
+		return prefixLength, "", nil, nil
+	}
+
+	var gateway string
+	var nameservers []string
+	for _, opt := range result[0].Options {
+		switch opt.Name {
+		case "routers":
+			gateway = opt.Value
+		case "domain-name-servers":
+			nameservers = strings.Split(opt.Value, ",")
+		}
+	}
+	return prefixLength, gateway, nameservers, nil
+}
+
+func (a *infobloxAllocator) Release(ctx context.Context, req AllocationRequest) error {
+	var records []struct {
+		Ref string `json:"_ref"`
+	}
+	if err := a.do(ctx, http.MethodGet, fmt.Sprintf("/record:host?name=%s", req.Owner), nil, &records); err != nil {
+		return fmt.Errorf("could not look up Infoblox record for %q: %v", req.Owner, err)
+	}
+	for _, record := range records {
+		if err := a.do(ctx, http.MethodDelete, "/"+record.Ref, nil, nil); err != nil {
+			return fmt.Errorf("could not release Infoblox record for %q: %v", req.Owner, err)
+		}
+	}
+	return nil
+}
+
+func (a *infobloxAllocator) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	httpReq, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, jsonReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.SetBasicAuth(a.username, a.password)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d from Infoblox", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+// Package ipam provides a pluggable source of static IP addresses for
+// machines that opt out of DHCP-based bootstrap. Implementations back onto
+// an in-cluster IP pool ConfigMap or an external IPAM system
+// (Infoblox, phpIPAM). Allocate is called once per VM, by the MCM vSphere
+// driver, using the AllocationRequest.Owner as an idempotency key so a VM
+// re-created after a crash gets its address back rather than a new one;
+// this extension only passes the backing IPAM source's configuration
+// through to the driver (see pkg/controller/worker.staticIPAMSpec).
+package ipam
+
+import "context"
+
+// AllocationRequest identifies the owner that a lease is allocated for, so
+// implementations can make the allocation idempotent across retries.
+type AllocationRequest struct {
+	// Owner is a stable identifier for the individual machine (VM) that
+	// the lease is allocated for, used as the allocation's idempotency
+	// key.
+	Owner string
+	// Network is the name of the IP network/segment to allocate from, as
+	// understood by the backing IPAM source.
+	Network string
+}
+
+// Lease is a single allocated static IP address and the network parameters
+// it must be configured with.
+type Lease struct {
+	// IPAddress is the allocated address, without prefix length.
+	IPAddress string
+	// PrefixLength is the network's CIDR prefix length.
+	PrefixLength int
+	// Gateway is the default gateway for the allocated address.
+	Gateway string
+	// Nameservers are the DNS servers to configure alongside the address.
+	Nameservers []string
+}
+
+// Allocator allocates and releases static IP leases for machines.
+type Allocator interface {
+	// Allocate returns the lease for the given request, allocating a new
+	// one if none exists yet for its Owner.
+	Allocate(ctx context.Context, req AllocationRequest) (*Lease, error)
+	// Release gives up the lease held for the given request's Owner, if
+	// any. It is a no-op if no lease is held.
+	Release(ctx context.Context, req AllocationRequest) error
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package ipam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// phpIPAMAllocator allocates addresses via the phpIPAM REST API's
+// "first free address" endpoint for a fixed section/subnet.
+type phpIPAMAllocator struct {
+	httpClient *http.Client
+	endpoint   string
+	appID      string
+	username   string
+	password   string
+}
+
+// NewPhpIPAMAllocator creates an Allocator backed by a phpIPAM instance
+// reachable at endpoint, authenticating as appID.
+func NewPhpIPAMAllocator(endpoint, appID, username, password string) Allocator {
+	return &phpIPAMAllocator{
+		httpClient: http.DefaultClient,
+		endpoint:   endpoint,
+		appID:      appID,
+		username:   username,
+		password:   password,
+	}
+}
+
+func (a *phpIPAMAllocator) Allocate(ctx context.Context, req AllocationRequest) (*Lease, error) {
+	if lease, ok, err := a.existingLease(ctx, req); err != nil {
+		return nil, err
+	} else if ok {
+		return lease, nil
+	}
+
+	prefixLength, gateway, nameservers, err := a.subnetParams(ctx, req.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data string `json:"data"`
+	}
+	path := fmt.Sprintf("/api/%s/subnets/%s/first_free/", a.appID, req.Network)
+	if err := a.do(ctx, http.MethodPost, path, map[string]string{"description": req.Owner}, &result); err != nil {
+		return nil, fmt.Errorf("could not allocate address for %q from phpIPAM: %v", req.Owner, err)
+	}
+
+	return &Lease{
+		IPAddress:    result.Data,
+		PrefixLength: prefixLength,
+		Gateway:      gateway,
+		Nameservers:  nameservers,
+	}, nil
+}
+
+// existingLease looks up an address already allocated in this subnet whose
+// description matches req.Owner, so Allocate can be called repeatedly
+// (e.g. on every worker reconcile) without grabbing a new "first free"
+// address from the subnet each time.
+func (a *phpIPAMAllocator) existingLease(ctx context.Context, req AllocationRequest) (*Lease, bool, error) {
+	var result struct {
+		Data []struct {
+			IP          string `json:"ip"`
+			Description string `json:"description"`
+		} `json:"data"`
+	}
+	path := fmt.Sprintf("/api/%s/subnets/%s/addresses/", a.appID, req.Network)
+	if err := a.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, false, fmt.Errorf("could not look up existing phpIPAM addresses for %q: %v", req.Owner, err)
+	}
+
+	for _, addr := range result.Data {
+		if addr.Description == req.Owner {
+			prefixLength, gateway, nameservers, err := a.subnetParams(ctx, req.Network)
+			if err != nil {
+				return nil, false, err
+			}
+			return &Lease{
+				IPAddress:    addr.IP,
+				PrefixLength: prefixLength,
+				Gateway:      gateway,
+				Nameservers:  nameservers,
+			}, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// subnetParams looks up the CIDR mask, gateway and nameservers configured
+// on the given phpIPAM subnet, so leases carry a usable gateway and
+// nameservers alongside the allocated address.
+func (a *phpIPAMAllocator) subnetParams(ctx context.Context, subnetID string) (int, string, []string, error) {
+	var result struct {
+		Data struct {
+			Mask    string `json:"mask"`
+			Gateway struct {
+				IPAddr string `json:"ip_addr"`
+			} `json:"gateway"`
+			Nameservers struct {
+				Namesrv1 string `json:"namesrv1"`
+			} `json:"nameservers"`
+		} `json:"data"`
+	}
+	path := fmt.Sprintf("/api/%s/subnets/%s/", a.appID, subnetID)
+	if err := a.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return 0, "", nil, fmt.Errorf("could not read phpIPAM subnet %q: %v", subnetID, err)
+	}
+
+	prefixLength, err := strconv.Atoi(result.Data.Mask)
+	if err != nil {
+		return 0, "", nil, fmt.Errorf("phpIPAM subnet %q has invalid mask %q: %v", subnetID, result.Data.Mask, err)
+	}
+
+	var nameservers []string
+	if result.Data.Nameservers.Namesrv1 != "" {
+		nameservers = strings.Split(result.Data.Nameservers.Namesrv1, ";")
+	}
+
+	return prefixLength, result.Data.Gateway.IPAddr, nameservers, nil
+}
+
+func (a *phpIPAMAllocator) Release(ctx context.Context, req AllocationRequest) error {
+	lease, ok, err := a.existingLease(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	path := fmt.Sprintf("/api/%s/addresses/%s/%s/", a.appID, lease.IPAddress, req.Network)
+	return a.do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+func (a *phpIPAMAllocator) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, a.endpoint+path, jsonReader(payload))
+	if err != nil {
+		return err
+	}
+	httpReq.SetBasicAuth(a.username, a.password)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d from phpIPAM", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package ipam
+
+import (
+	"fmt"
+
+	apisvsphere "github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewAllocatorForConfig builds the Allocator described by config. namespace
+// is the shoot namespace, used to resolve the in-cluster pool and
+// credential secrets referenced by config.
+func NewAllocatorForConfig(c client.Client, namespace string, config *apisvsphere.StaticIPAMConfig, credentials map[string]string) (Allocator, error) {
+	switch config.Source {
+	case apisvsphere.IPAMSourceInCluster:
+		if config.InCluster == nil {
+			return nil, fmt.Errorf("staticIPAM.inCluster must be set when source is %q", apisvsphere.IPAMSourceInCluster)
+		}
+		return NewInClusterAllocator(c, namespace, config.InCluster.PoolName), nil
+
+	case apisvsphere.IPAMSourceInfoblox:
+		if config.Infoblox == nil {
+			return nil, fmt.Errorf("staticIPAM.infoblox must be set when source is %q", apisvsphere.IPAMSourceInfoblox)
+		}
+		return NewInfobloxAllocator(config.Infoblox.Host, config.Infoblox.NetworkView, credentials["username"], credentials["password"]), nil
+
+	case apisvsphere.IPAMSourcePhpIPAM:
+		if config.PhpIPAM == nil {
+			return nil, fmt.Errorf("staticIPAM.phpIPAM must be set when source is %q", apisvsphere.IPAMSourcePhpIPAM)
+		}
+		return NewPhpIPAMAllocator(config.PhpIPAM.Endpoint, config.PhpIPAM.AppID, credentials["username"], credentials["password"]), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported static IPAM source %q", config.Source)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package machinepool
+
+import (
+	"context"
+	"fmt"
+
+	apisvsphere "github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// AntiAffinityReconciler ensures that a "separate virtual machines" DRS rule
+// exists for a MachinePool, keyed by the pool's deployment name.
+type AntiAffinityReconciler struct {
+	Cluster *object.ClusterComputeResource
+}
+
+// NewAntiAffinityReconciler creates an AntiAffinityReconciler for the given
+// compute cluster.
+func NewAntiAffinityReconciler(cluster *object.ClusterComputeResource) *AntiAffinityReconciler {
+	return &AntiAffinityReconciler{Cluster: cluster}
+}
+
+// EnsureRule ensures that the DRS anti-affinity rule for the given pool
+// exists and references the given VM references. It is a no-op when the
+// policy is AntiAffinityPolicyNone or empty.
+func (r *AntiAffinityReconciler) EnsureRule(ctx context.Context, ruleName string, policy apisvsphere.AntiAffinityPolicy, vms []types.ManagedObjectReference) error {
+	if policy == "" || policy == apisvsphere.AntiAffinityPolicyNone {
+		return nil
+	}
+	if policy != apisvsphere.AntiAffinityPolicySpreadHosts {
+		return fmt.Errorf("unsupported anti-affinity policy %q", policy)
+	}
+
+	existing, err := r.Cluster.Configuration(ctx)
+	if err != nil {
+		return fmt.Errorf("could not read configuration of cluster %q: %v", r.Cluster.Name(), err)
+	}
+
+	operation := types.ArrayUpdateOperationAdd
+	var key int32
+	for _, rule := range existing.Rule {
+		if info, ok := rule.(*types.ClusterAntiAffinityRuleSpec); ok && info.Name == ruleName {
+			operation = types.ArrayUpdateOperationEdit
+			key = info.Key
+			break
+		}
+	}
+
+	spec := types.ClusterConfigSpecEx{
+		RulesSpec: []types.ClusterRuleSpec{
+			{
+				ArrayUpdateSpec: types.ArrayUpdateSpec{
+					Operation: operation,
+				},
+				Info: &types.ClusterAntiAffinityRuleSpec{
+					ClusterRuleInfo: types.ClusterRuleInfo{
+						Key:       key,
+						Name:      ruleName,
+						Enabled:   types.NewBool(true),
+						Mandatory: types.NewBool(false),
+					},
+					Vm: vms,
+				},
+			},
+		},
+	}
+
+	task, err := r.Cluster.Reconfigure(ctx, &spec, true)
+	if err != nil {
+		return fmt.Errorf("could not reconfigure cluster with anti-affinity rule %q: %v", ruleName, err)
+	}
+
+	if err := task.Wait(ctx); err != nil {
+		return fmt.Errorf("anti-affinity rule %q reconfiguration failed: %v", ruleName, err)
+	}
+
+	return nil
+}
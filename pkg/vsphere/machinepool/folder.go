@@ -0,0 +1,99 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+// Package machinepool implements vSphere-native placement for Gardener
+// worker pools that opt out of plain per-VM MCM scaling: a dedicated VM
+// folder per pool and a DRS anti-affinity rule spreading replicas across
+// ESXi hosts.
+package machinepool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	apisvsphere "github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+)
+
+// Annotation is set on a MachineDeployment to opt it into MachinePool
+// reconciliation by the machinepool controller.
+const Annotation = "vsphere.provider.extensions.gardener.cloud/machine-pool"
+
+// PoolIdentity identifies a worker pool that a MachinePool folder/rule is
+// reconciled for.
+type PoolIdentity struct {
+	// Namespace is the technical shoot namespace in the seed.
+	Namespace string
+	// Pool is the name of the worker pool.
+	Pool string
+}
+
+// FolderName renders the folder name for the given pool according to the
+// FolderTemplate, falling back to "<namespace>-<pool>" if no template is
+// configured.
+func FolderName(identity PoolIdentity, folderTemplate *apisvsphere.FolderTemplate) (string, error) {
+	tmpl := "{{.Namespace}}-{{.Pool}}"
+	if folderTemplate != nil && folderTemplate.NameTemplate != "" {
+		tmpl = folderTemplate.NameTemplate
+	}
+
+	t, err := template.New("folderName").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid folder name template %q: %v", tmpl, err)
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, identity); err != nil {
+		return "", fmt.Errorf("could not render folder name template %q: %v", tmpl, err)
+	}
+
+	return sb.String(), nil
+}
+
+// FolderReconciler ensures that the VM folder backing a MachinePool exists.
+type FolderReconciler struct {
+	Finder *find.Finder
+}
+
+// NewFolderReconciler creates a FolderReconciler for the given datacenter
+// finder.
+func NewFolderReconciler(finder *find.Finder) *FolderReconciler {
+	return &FolderReconciler{Finder: finder}
+}
+
+// EnsureFolder ensures that a folder with the given name exists below
+// parentPath, creating it if necessary, and returns it.
+func (r *FolderReconciler) EnsureFolder(ctx context.Context, parentPath, name string) (*object.Folder, error) {
+	if existing, err := r.Finder.Folder(ctx, parentPath+"/"+name); err == nil {
+		return existing, nil
+	}
+
+	parent, err := r.Finder.Folder(ctx, parentPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve parent folder %q: %v", parentPath, err)
+	}
+
+	folder, err := parent.CreateFolder(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not create folder %q under %q: %v", name, parentPath, err)
+	}
+
+	return folder, nil
+}
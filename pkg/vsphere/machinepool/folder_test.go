@@ -0,0 +1,51 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package machinepool
+
+import (
+	"testing"
+
+	apisvsphere "github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere"
+)
+
+func TestFolderNameDefaultTemplate(t *testing.T) {
+	name, err := FolderName(PoolIdentity{Namespace: "shoot--foo--bar", Pool: "worker"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "shoot--foo--bar-worker" {
+		t.Errorf("expected %q, got %q", "shoot--foo--bar-worker", name)
+	}
+}
+
+func TestFolderNameCustomTemplate(t *testing.T) {
+	template := &apisvsphere.FolderTemplate{NameTemplate: "{{.Pool}}.{{.Namespace}}"}
+	name, err := FolderName(PoolIdentity{Namespace: "shoot--foo--bar", Pool: "worker"}, template)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "worker.shoot--foo--bar" {
+		t.Errorf("expected %q, got %q", "worker.shoot--foo--bar", name)
+	}
+}
+
+func TestFolderNameInvalidTemplate(t *testing.T) {
+	template := &apisvsphere.FolderTemplate{NameTemplate: "{{.Pool"}
+	if _, err := FolderName(PoolIdentity{Namespace: "shoot--foo--bar", Pool: "worker"}, template); err == nil {
+		t.Fatal("expected an error for an invalid folder name template")
+	}
+}
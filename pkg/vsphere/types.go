@@ -0,0 +1,129 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+// Package vsphere contains constants and helpers shared across the vSphere
+// provider extension.
+package vsphere
+
+import (
+	"encoding/json"
+	"fmt"
+
+	apisvsphere "github.com/gardener/gardener-extension-provider-vsphere/pkg/apis/vsphere"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// Host is the secret data key for the vCenter host.
+	Host = "vsphereHost"
+	// Username is the secret data key for the vCenter username.
+	//
+	// Deprecated: only used as a fallback for single-vCenter secrets that
+	// do not set the Credentials key. See Credentials.
+	Username = "vsphereUsername"
+	// Password is the secret data key for the vCenter password.
+	//
+	// Deprecated: see Username.
+	Password = "vspherePassword"
+	// InsecureSSL is the secret data key controlling vCenter certificate
+	// verification.
+	InsecureSSL = "vsphereInsecureSSL"
+	// CredentialsKey is the secret data key holding the JSON-encoded map of
+	// named credential blocks (vCenter name -> CredentialsBinding), used
+	// for multi-vCenter worker pools.
+	CredentialsKey = "vsphereCredentials"
+
+	// InternalChartsPath is the path to the internal charts shipped with
+	// this extension.
+	InternalChartsPath = "charts/internal"
+)
+
+// Credentials contains the vSphere credentials extracted from a Kubernetes
+// Secret, keyed by vCenter name.
+type Credentials struct {
+	byVCenter map[string]CredentialsBinding
+}
+
+// CredentialsBinding contains a single username/password pair.
+type CredentialsBinding struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// VsphereMCM returns the credentials to be used by the machine-controller-manager
+// for the region's default (single) vCenter.
+//
+// Deprecated: use ForVCenter for multi-vCenter secrets.
+func (c *Credentials) VsphereMCM() CredentialsBinding {
+	return c.byVCenter[apisvsphere.DefaultVCenterName]
+}
+
+// ForVCenter returns the credentials for the named vCenter. If the secret
+// only carried a single, unnamed credential block (legacy format), that
+// block is returned regardless of name.
+func (c *Credentials) ForVCenter(name string) (CredentialsBinding, error) {
+	if binding, ok := c.byVCenter[name]; ok {
+		return binding, nil
+	}
+	if binding, ok := c.byVCenter[apisvsphere.DefaultVCenterName]; ok && len(c.byVCenter) == 1 {
+		return binding, nil
+	}
+	return CredentialsBinding{}, fmt.Errorf("no credentials found for vCenter %q", name)
+}
+
+// ExtractCredentials extracts the vSphere credentials from the given secret.
+// Secrets materialized for multi-vCenter worker pools carry a single
+// CredentialsKey entry that JSON-decodes into a map of named credential
+// blocks; older, single-vCenter secrets carry plain Username/Password keys
+// and are exposed under apisvsphere.DefaultVCenterName.
+func ExtractCredentials(secret *corev1.Secret) (*Credentials, error) {
+	if raw, ok := secret.Data[CredentialsKey]; ok {
+		byVCenter := map[string]CredentialsBinding{}
+		if err := json.Unmarshal(raw, &byVCenter); err != nil {
+			return nil, fmt.Errorf("could not decode %q field in secret: %v", CredentialsKey, err)
+		}
+		return &Credentials{byVCenter: byVCenter}, nil
+	}
+
+	username, ok := secret.Data[Username]
+	if !ok {
+		return nil, fmt.Errorf("missing %q field in secret", Username)
+	}
+	password, ok := secret.Data[Password]
+	if !ok {
+		return nil, fmt.Errorf("missing %q field in secret", Password)
+	}
+
+	return &Credentials{
+		byVCenter: map[string]CredentialsBinding{
+			apisvsphere.DefaultVCenterName: {
+				Username: string(username),
+				Password: string(password),
+			},
+		},
+	}, nil
+}
+
+// MarshalIndent is a small helper used across the extension to render
+// debug-friendly JSON for provider objects in log messages.
+func MarshalIndent(v interface{}) string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("<error marshalling: %v>", err)
+	}
+	return string(data)
+}
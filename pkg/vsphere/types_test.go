@@ -0,0 +1,59 @@
+/*
+ * Copyright 2019 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ *
+ */
+
+package vsphere
+
+import "testing"
+
+func TestCredentialsForVCenterNamedMatch(t *testing.T) {
+	credentials := &Credentials{byVCenter: map[string]CredentialsBinding{
+		"vc-a": {Username: "user-a", Password: "pass-a"},
+		"vc-b": {Username: "user-b", Password: "pass-b"},
+	}}
+
+	binding, err := credentials.ForVCenter("vc-b")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if binding.Username != "user-b" || binding.Password != "pass-b" {
+		t.Errorf("got binding %+v, want username/password for vc-b", binding)
+	}
+}
+
+func TestCredentialsForVCenterSingleEntryFallback(t *testing.T) {
+	credentials := &Credentials{byVCenter: map[string]CredentialsBinding{
+		"default": {Username: "user", Password: "pass"},
+	}}
+
+	binding, err := credentials.ForVCenter("some-other-name")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if binding.Username != "user" || binding.Password != "pass" {
+		t.Errorf("got binding %+v, want the sole credential block", binding)
+	}
+}
+
+func TestCredentialsForVCenterNotFound(t *testing.T) {
+	credentials := &Credentials{byVCenter: map[string]CredentialsBinding{
+		"vc-a": {Username: "user-a", Password: "pass-a"},
+		"vc-b": {Username: "user-b", Password: "pass-b"},
+	}}
+
+	if _, err := credentials.ForVCenter("vc-c"); err == nil {
+		t.Fatal("expected an error for an unknown vCenter name with more than one credential block")
+	}
+}